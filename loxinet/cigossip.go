@@ -0,0 +1,551 @@
+/*
+ * Copyright (c) 2022 NetLOX Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loxinet
+
+import (
+	"encoding/json"
+	"math"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	cmn "github.com/loxilb-io/loxilb/common"
+	tk "github.com/loxilb-io/loxilib"
+)
+
+// Gossip protocol tunables. These follow the SWIM paper defaults as
+// popularized by hashicorp/memberlist - one protocol period per second,
+// k=3 indirect probes before a peer is marked suspect.
+const (
+	gossipProtoPeriod  = 1 * time.Second
+	gossipProtoTimeout = 300 * time.Millisecond
+	gossipIndirectK    = 3
+	gossipUDPBufSz     = 4096
+
+	gossipStateAlive   = "alive"
+	gossipStateSuspect = "suspect"
+	gossipStateDead    = "dead"
+)
+
+// gossip message types piggybacked over UDP
+const (
+	gossipMsgPing = iota
+	gossipMsgAck
+	gossipMsgIndirectPing
+)
+
+// gossipUpdate - a single membership diff piggybacked on ping/ack traffic
+type gossipUpdate struct {
+	Node        string `json:"node"`
+	State       string `json:"state"`
+	Incarnation uint64 `json:"incarnation"`
+}
+
+// gossipMsg - wire format for the gossip UDP transport
+type gossipMsg struct {
+	Type    int            `json:"type"`
+	From    string         `json:"from"`
+	Target  string         `json:"target,omitempty"`
+	Updates []gossipUpdate `json:"updates,omitempty"`
+}
+
+// gossipH - gossip layer state for a CIStateH instance.
+//
+// NodeMap/ClusterMap are shared with the rest of the cluster subsystem and
+// are always accessed under mh.mtx, same as BFDSessionNotify/CIStateUpdate.
+// incarnation/updates/suspects/tombstones are gossip-private bookkeeping
+// touched by both probeLoop and recvLoop, guarded by mu. ackWait is guarded
+// separately by ackMu since it is on the hot path of every send().
+type gossipH struct {
+	ci     *CIStateH
+	self   string
+	conn   *net.UDPConn
+	stopCh chan struct{}
+
+	mu          sync.Mutex
+	incarnation uint64
+	updates     []gossipUpdate
+	suspects    map[string]time.Time
+	// tombstones remembers the incarnation a peer was last declared dead at,
+	// even after it is dropped from NodeMap, so a stale/re-broadcast "alive"
+	// update (or a seed re-announcement at incarnation 0) can't resurrect it.
+	// A node may still rejoin by gossiping a strictly newer incarnation.
+	tombstones map[string]uint64
+
+	ackMu   sync.Mutex
+	ackWait map[string]chan struct{}
+}
+
+// startGossip - bootstrap the SWIM-style gossip layer from the seed list
+// and spawn the probe loop. Convergence (join/alive/dead) drives
+// ClusterNodeAdd/ClusterNodeDelete and per-peer BFD session lifecycle.
+func (ci *CIStateH) startGossip() {
+	addr, err := net.ResolveUDPAddr("udp", ci.GossipBindAddr)
+	if err != nil {
+		tk.LogIt(tk.LogError, "KA - gossip bind addr %s invalid: %s\n", ci.GossipBindAddr, err)
+		return
+	}
+
+	conn, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		tk.LogIt(tk.LogError, "KA - gossip listen failed: %s\n", err)
+		return
+	}
+
+	gh := &gossipH{
+		ci:         ci,
+		self:       ci.GossipBindAddr,
+		conn:       conn,
+		suspects:   make(map[string]time.Time),
+		tombstones: make(map[string]uint64),
+		ackWait:    make(map[string]chan struct{}),
+		stopCh:     make(chan struct{}),
+	}
+	ci.gossipH = gh
+
+	for _, seed := range ci.SeedPeers {
+		if seed == gh.self {
+			continue
+		}
+		gh.applyUpdate(gossipUpdate{Node: seed, State: gossipStateAlive, Incarnation: 0})
+	}
+
+	// Queue an alive update about ourselves so we're piggybacked outward on
+	// the first probe - without this, discovery only ever flows from a
+	// joiner to its seeds, and a seed never learns about a new joiner that
+	// isn't itself in the seed's own seed list.
+	gh.selfAnnounce()
+
+	go gh.recvLoop()
+	go gh.probeLoop()
+
+	tk.LogIt(tk.LogInfo, "KA - gossip started on %s with %d seed(s)\n", gh.self, len(ci.SeedPeers))
+}
+
+// probeLoop - picks a random member every protocol period and pings it,
+// falling back to k indirect probes before declaring it suspect, and
+// finally dead after a suspicion timeout of ~log(N)*period.
+func (gh *gossipH) probeLoop() {
+	ticker := time.NewTicker(gossipProtoPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-gh.stopCh:
+			return
+		case <-ticker.C:
+			mh.mtx.Lock()
+			gh.checkSuspectsLocked()
+			peer := gh.randomPeerLocked()
+			mh.mtx.Unlock()
+
+			// Re-announce ourselves with a strictly higher incarnation on
+			// every period, not just at startup - this is also what lets a
+			// peer wrongly marked dead elsewhere climb back above that
+			// peer's tombstone and rejoin instead of being locked out forever.
+			gh.selfAnnounce()
+			pending := gh.drainUpdatesLocked()
+
+			if peer == "" {
+				continue
+			}
+			if gh.ping(peer, pending) {
+				gh.refute(peer)
+			} else {
+				gh.indirectPing(peer, pending)
+			}
+		}
+	}
+}
+
+// randomPeerLocked - picks a random live member from CIStateH.NodeMap.
+// Caller must hold mh.mtx.
+func (gh *gossipH) randomPeerLocked() string {
+	ci := gh.ci
+	peers := make([]string, 0, len(ci.NodeMap))
+	for addr, node := range ci.NodeMap {
+		if node.GossipState == gossipStateDead || addr == gh.self {
+			continue
+		}
+		peers = append(peers, addr)
+	}
+	if len(peers) == 0 {
+		return ""
+	}
+	return peers[rand.Intn(len(peers))]
+}
+
+// ping - sends a direct ping and waits up to gossipProtoTimeout for an ack
+func (gh *gossipH) ping(peer string, pending []gossipUpdate) bool {
+	return gh.send(peer, gossipMsg{Type: gossipMsgPing, From: gh.self, Updates: pending}, true)
+}
+
+// indirectPing - asks k other members to probe peer on our behalf before
+// declaring it suspect
+func (gh *gossipH) indirectPing(peer string, pending []gossipUpdate) {
+	mh.mtx.Lock()
+	ci := gh.ci
+	helpers := make([]string, 0, gossipIndirectK)
+	for addr, node := range ci.NodeMap {
+		if addr == peer || addr == gh.self || node.GossipState == gossipStateDead {
+			continue
+		}
+		helpers = append(helpers, addr)
+		if len(helpers) == gossipIndirectK {
+			break
+		}
+	}
+	mh.mtx.Unlock()
+
+	acked := false
+	for _, h := range helpers {
+		if gh.send(h, gossipMsg{Type: gossipMsgIndirectPing, From: gh.self, Target: peer, Updates: pending}, true) {
+			acked = true
+			break
+		}
+	}
+
+	if acked {
+		gh.refute(peer)
+	} else {
+		gh.markSuspect(peer)
+	}
+}
+
+// selfAnnounce - queues an alive update about ourselves at a strictly
+// higher incarnation than anything we've announced before, to be
+// piggybacked on the next outbound ping/ack
+func (gh *gossipH) selfAnnounce() {
+	gh.mu.Lock()
+	gh.incarnation++
+	incarnation := gh.incarnation
+	gh.queueUpdateLocked(gossipUpdate{Node: gh.self, State: gossipStateAlive, Incarnation: incarnation})
+	gh.mu.Unlock()
+}
+
+// refute - clears a peer's suspicion after it responds to a probe, so a
+// peer that recovers within a single protocol period isn't declared dead
+// anyway on the next tick
+func (gh *gossipH) refute(peer string) {
+	mh.mtx.Lock()
+	defer mh.mtx.Unlock()
+
+	gh.mu.Lock()
+	_, wasSuspect := gh.suspects[peer]
+	delete(gh.suspects, peer)
+	gh.mu.Unlock()
+	if !wasSuspect {
+		return
+	}
+
+	if node, ok := gh.ci.NodeMap[peer]; ok {
+		node.GossipState = gossipStateAlive
+		node.LastSeen = time.Now()
+	}
+	gh.ci.Log.Info("gossip peer refuted suspicion", "peer", peer)
+}
+
+// markSuspect - starts the suspicion timer for a peer (an already running
+// timer is left as is); it is declared dead once the timer reaches
+// log2(N+1)+1 protocol periods, rounded down, N being len(NodeMap)
+func (gh *gossipH) markSuspect(peer string) {
+	mh.mtx.Lock()
+	defer mh.mtx.Unlock()
+	gh.markSuspectLocked(peer)
+}
+
+// markSuspectLocked - caller must hold mh.mtx
+func (gh *gossipH) markSuspectLocked(peer string) {
+	gh.mu.Lock()
+	_, already := gh.suspects[peer]
+	gh.mu.Unlock()
+	if already {
+		return
+	}
+
+	if node, ok := gh.ci.NodeMap[peer]; ok {
+		node.GossipState = gossipStateSuspect
+	}
+
+	gh.mu.Lock()
+	gh.suspects[peer] = time.Now()
+	gh.incarnation++
+	incarnation := gh.incarnation
+	gh.queueUpdateLocked(gossipUpdate{Node: peer, State: gossipStateSuspect, Incarnation: incarnation})
+	gh.mu.Unlock()
+
+	gh.ci.Log.Warn("gossip peer suspect", "peer", peer, "incarnation", incarnation)
+}
+
+// checkSuspectsLocked - promotes timed-out suspects to dead.
+// Caller must hold mh.mtx.
+func (gh *gossipH) checkSuspectsLocked() {
+	n := len(gh.ci.NodeMap)
+	if n < 1 {
+		n = 1
+	}
+	suspicionTimeout := time.Duration(math.Log2(float64(n+1))+1) * gossipProtoPeriod
+
+	gh.mu.Lock()
+	expired := make([]string, 0)
+	for peer, since := range gh.suspects {
+		if time.Since(since) < suspicionTimeout {
+			continue
+		}
+		expired = append(expired, peer)
+		delete(gh.suspects, peer)
+	}
+	gh.mu.Unlock()
+
+	for _, peer := range expired {
+		gh.mu.Lock()
+		gh.incarnation++
+		incarnation := gh.incarnation
+		gh.mu.Unlock()
+		gh.applyUpdateLocked(gossipUpdate{Node: peer, State: gossipStateDead, Incarnation: incarnation})
+	}
+}
+
+// recvLoop - handles inbound ping/ack/indirect-ping traffic and the
+// membership diffs piggybacked on them. This is the sole reader of gh.conn,
+// so acks are matched here via ackWait rather than a second reader racing
+// with this loop over the shared socket.
+func (gh *gossipH) recvLoop() {
+	buf := make([]byte, gossipUDPBufSz)
+	for {
+		select {
+		case <-gh.stopCh:
+			return
+		default:
+		}
+
+		n, _, err := gh.conn.ReadFromUDP(buf)
+		if err != nil {
+			continue
+		}
+
+		var msg gossipMsg
+		if err := json.Unmarshal(buf[:n], &msg); err != nil {
+			continue
+		}
+
+		for _, u := range msg.Updates {
+			gh.applyUpdate(u)
+		}
+
+		switch msg.Type {
+		case gossipMsgPing:
+			gh.noteSender(msg.From)
+			gh.send(msg.From, gossipMsg{Type: gossipMsgAck, From: gh.self}, false)
+		case gossipMsgIndirectPing:
+			// Probe from a separate goroutine: the target's ack is delivered
+			// by this loop, which must keep reading while the probe waits.
+			go func(from, target string) {
+				if gh.ping(target, nil) {
+					gh.send(from, gossipMsg{Type: gossipMsgAck, From: gh.self}, false)
+				}
+			}(msg.From, msg.Target)
+		case gossipMsgAck:
+			gh.ackMu.Lock()
+			ch, ok := gh.ackWait[msg.From]
+			gh.ackMu.Unlock()
+			if ok {
+				select {
+				case ch <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}
+}
+
+// noteSender - adds a previously-unknown ping sender to NodeMap, so a
+// member is discovered as soon as it pings us even if it isn't in our own
+// static seed list
+func (gh *gossipH) noteSender(peer string) {
+	if peer == "" || peer == gh.self {
+		return
+	}
+
+	mh.mtx.Lock()
+	_, known := gh.ci.NodeMap[peer]
+	mh.mtx.Unlock()
+	if known {
+		return
+	}
+
+	gh.applyUpdate(gossipUpdate{Node: peer, State: gossipStateAlive, Incarnation: 0})
+}
+
+// send - transmits a gossip message to peer, optionally blocking for an ack.
+// The ack itself is delivered by recvLoop via ackWait - send never reads
+// gh.conn, so there is exactly one reader of the socket.
+func (gh *gossipH) send(peer string, msg gossipMsg, wantAck bool) bool {
+	addr, err := net.ResolveUDPAddr("udp", peer)
+	if err != nil {
+		return false
+	}
+
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return false
+	}
+
+	if _, err := gh.conn.WriteToUDP(b, addr); err != nil {
+		return false
+	}
+
+	if !wantAck {
+		return true
+	}
+
+	ch := make(chan struct{}, 1)
+	gh.ackMu.Lock()
+	gh.ackWait[peer] = ch
+	gh.ackMu.Unlock()
+	defer func() {
+		gh.ackMu.Lock()
+		delete(gh.ackWait, peer)
+		gh.ackMu.Unlock()
+	}()
+
+	select {
+	case <-ch:
+		return true
+	case <-time.After(gossipProtoTimeout):
+		return false
+	}
+}
+
+// queueUpdateLocked - queues a membership diff to be piggybacked on the
+// next outbound ping/ack. Caller must hold gh.mu.
+func (gh *gossipH) queueUpdateLocked(u gossipUpdate) {
+	gh.updates = append(gh.updates, u)
+}
+
+// drainUpdatesLocked - returns and clears the pending piggyback buffer.
+// Called once per probe tick alongside checkSuspectsLocked/randomPeerLocked.
+func (gh *gossipH) drainUpdatesLocked() []gossipUpdate {
+	gh.mu.Lock()
+	defer gh.mu.Unlock()
+	u := gh.updates
+	gh.updates = nil
+	return u
+}
+
+// applyUpdate - applyUpdateLocked, acquiring mh.mtx for the caller
+func (gh *gossipH) applyUpdate(u gossipUpdate) {
+	mh.mtx.Lock()
+	defer mh.mtx.Unlock()
+	gh.applyUpdateLocked(u)
+}
+
+// applyUpdateLocked - applies a membership diff to NodeMap if its
+// incarnation is newer than what we already know, driving
+// ClusterNodeAdd/Delete and the per-peer BFD session on every join/dead
+// convergence event. Caller must hold mh.mtx.
+func (gh *gossipH) applyUpdateLocked(u gossipUpdate) {
+	ci := gh.ci
+
+	node, known := ci.NodeMap[u.Node]
+	// Incarnation 0 is only a valid bootstrap value for a node we've never
+	// seen before (a seed re-announcement or a fresh ping sender); once a
+	// node is known, any update must carry a strictly newer incarnation or
+	// it's stale, incarnation 0 included - otherwise a straggling seed
+	// re-announce can regress an already-converged node's incarnation.
+	if known && node.Incarnation >= u.Incarnation {
+		return
+	}
+
+	if u.State == gossipStateAlive {
+		gh.mu.Lock()
+		deadInc, tombstoned := gh.tombstones[u.Node]
+		gh.mu.Unlock()
+		if tombstoned && u.Incarnation <= deadInc {
+			return
+		}
+	}
+
+	switch u.State {
+	case gossipStateAlive:
+		if !known {
+			ip := net.ParseIP(hostOf(u.Node))
+			if ip == nil {
+				return
+			}
+			if _, err := ci.ClusterNodeAdd(cmn.ClusterNodeMod{Addr: ip}); err != nil {
+				return
+			}
+			// ClusterNodeAdd keys NodeMap by IP; gossip tracks members by
+			// their host:port gossip address, so re-key the new entry.
+			node = ci.NodeMap[ip.String()]
+			if node == nil {
+				return
+			}
+			delete(ci.NodeMap, ip.String())
+			ci.NodeMap[u.Node] = node
+			source := ci.SourceIP.String()
+			if ci.SourceIP == nil || ci.SourceIP.IsUnspecified() {
+				source = hostOf(gh.self)
+			}
+			if err := ci.addBFDPeer(hostOf(u.Node), source, cmn.CIDefault); err != nil {
+				ci.Log.Error("gossip BFD peer add failed", "peer", u.Node, "error", err)
+			}
+			ci.Log.Info("gossip peer joined", "peer", u.Node, "incarnation", u.Incarnation)
+		}
+		node.GossipState = gossipStateAlive
+		node.Incarnation = u.Incarnation
+		node.LastSeen = time.Now()
+		gh.mu.Lock()
+		delete(gh.suspects, u.Node)
+		delete(gh.tombstones, u.Node)
+		gh.mu.Unlock()
+	case gossipStateSuspect:
+		if known {
+			node.GossipState = gossipStateSuspect
+			node.Incarnation = u.Incarnation
+		}
+	case gossipStateDead:
+		if known {
+			ci.delBFDPeer(hostOf(u.Node))
+			// ClusterNodeDelete looks nodes up by IP; move the entry back
+			// under that key so it is found and removed.
+			delete(ci.NodeMap, u.Node)
+			ci.NodeMap[node.Addr.String()] = node
+			_, _ = ci.ClusterNodeDelete(cmn.ClusterNodeMod{Addr: node.Addr})
+			ci.Log.Info("gossip peer dead", "peer", u.Node, "incarnation", u.Incarnation)
+		}
+		gh.mu.Lock()
+		delete(gh.suspects, u.Node)
+		gh.tombstones[u.Node] = u.Incarnation
+		gh.mu.Unlock()
+	}
+
+	gh.mu.Lock()
+	gh.queueUpdateLocked(u)
+	gh.mu.Unlock()
+}
+
+// hostOf - strips the port off a "host:port" gossip address
+func hostOf(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
@@ -0,0 +1,130 @@
+/*
+ * Copyright (c) 2022 NetLOX Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loxinet
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+)
+
+func newTestGossipH() *gossipH {
+	ci := &CIStateH{
+		NodeMap: make(map[string]*ClusterNode),
+		Log:     hclog.NewNullLogger(),
+	}
+	gh := &gossipH{
+		ci:         ci,
+		self:       "127.0.0.1:6000",
+		suspects:   make(map[string]time.Time),
+		tombstones: make(map[string]uint64),
+		ackWait:    make(map[string]chan struct{}),
+	}
+	ci.gossipH = gh
+	return gh
+}
+
+func TestApplyUpdateLockedIgnoresStaleIncarnation(t *testing.T) {
+	gh := newTestGossipH()
+	gh.ci.NodeMap["peer:6000"] = &ClusterNode{Addr: net.ParseIP("10.0.0.2"), GossipState: gossipStateAlive, Incarnation: 5}
+
+	gh.applyUpdateLocked(gossipUpdate{Node: "peer:6000", State: gossipStateSuspect, Incarnation: 3})
+
+	node := gh.ci.NodeMap["peer:6000"]
+	if node.GossipState != gossipStateAlive || node.Incarnation != 5 {
+		t.Fatalf("expected stale update to be ignored, got state=%s incarnation=%d", node.GossipState, node.Incarnation)
+	}
+}
+
+func TestApplyUpdateLockedTombstonesDeadPeerAgainstResurrection(t *testing.T) {
+	gh := newTestGossipH()
+	gh.ci.NodeMap["peer:6000"] = &ClusterNode{Addr: net.ParseIP("10.0.0.2"), GossipState: gossipStateAlive, Incarnation: 5}
+
+	gh.applyUpdateLocked(gossipUpdate{Node: "peer:6000", State: gossipStateDead, Incarnation: 6})
+	if _, known := gh.ci.NodeMap["peer:6000"]; known {
+		t.Fatalf("expected dead peer to be removed from NodeMap")
+	}
+	if inc, tombstoned := gh.tombstones["peer:6000"]; !tombstoned || inc != 6 {
+		t.Fatalf("expected dead peer tombstoned at incarnation 6, got %d tombstoned=%v", inc, tombstoned)
+	}
+
+	// A straggler/re-broadcast "alive" at or below the tombstoned incarnation
+	// must not resurrect the peer.
+	gh.applyUpdateLocked(gossipUpdate{Node: "peer:6000", State: gossipStateAlive, Incarnation: 6})
+	if _, known := gh.ci.NodeMap["peer:6000"]; known {
+		t.Fatalf("expected stale alive update to be rejected by the tombstone")
+	}
+}
+
+func TestApplyUpdateLockedRejectsIncarnationZeroForKnownNode(t *testing.T) {
+	gh := newTestGossipH()
+	gh.ci.NodeMap["peer:6000"] = &ClusterNode{Addr: net.ParseIP("10.0.0.2"), GossipState: gossipStateAlive, Incarnation: 5}
+
+	// A straggling seed re-announce (or fresh ping) at incarnation 0 must
+	// not regress a node we already know at a higher incarnation - 0 is
+	// only a valid bootstrap value for a node we've never seen before.
+	gh.applyUpdateLocked(gossipUpdate{Node: "peer:6000", State: gossipStateAlive, Incarnation: 0})
+
+	node := gh.ci.NodeMap["peer:6000"]
+	if node.Incarnation != 5 {
+		t.Fatalf("expected known node's incarnation to stay at 5, got %d", node.Incarnation)
+	}
+}
+
+func TestSelfAnnounceQueuesIncreasingIncarnation(t *testing.T) {
+	gh := newTestGossipH()
+
+	gh.selfAnnounce()
+	gh.selfAnnounce()
+
+	pending := gh.drainUpdatesLocked()
+	if len(pending) != 2 {
+		t.Fatalf("expected 2 queued self-announce updates, got %d", len(pending))
+	}
+	if pending[0].Node != gh.self || pending[0].State != gossipStateAlive {
+		t.Fatalf("expected self-announce to queue an alive update about gh.self, got %+v", pending[0])
+	}
+	if pending[1].Incarnation <= pending[0].Incarnation {
+		t.Fatalf("expected each self-announce to use a strictly higher incarnation, got %d then %d", pending[0].Incarnation, pending[1].Incarnation)
+	}
+}
+
+func TestRefuteClearsSuspicionOnSuccessfulProbe(t *testing.T) {
+	gh := newTestGossipH()
+	gh.ci.NodeMap["peer:6000"] = &ClusterNode{Addr: net.ParseIP("10.0.0.2"), GossipState: gossipStateSuspect}
+	gh.suspects["peer:6000"] = time.Now()
+
+	gh.refute("peer:6000")
+
+	if _, stillSuspect := gh.suspects["peer:6000"]; stillSuspect {
+		t.Fatalf("expected refute to clear the suspicion timer")
+	}
+	if gh.ci.NodeMap["peer:6000"].GossipState != gossipStateAlive {
+		t.Fatalf("expected refute to restore GossipState to alive")
+	}
+}
+
+func TestHostOf(t *testing.T) {
+	if got := hostOf("10.0.0.1:6000"); got != "10.0.0.1" {
+		t.Fatalf("expected hostOf to strip the port, got %q", got)
+	}
+	if got := hostOf("not-a-host-port"); got != "not-a-host-port" {
+		t.Fatalf("expected hostOf to pass through an unparseable address, got %q", got)
+	}
+}
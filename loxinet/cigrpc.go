@@ -0,0 +1,224 @@
+/*
+ * Copyright (c) 2022 NetLOX Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loxinet
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+
+	cmn "github.com/loxilb-io/loxilb/common"
+	pb "github.com/loxilb-io/loxilb/proto/cluster"
+	tk "github.com/loxilb-io/loxilib"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// grpcBroadcaster - fans out a HASMod-equivalent message to every watcher
+// subscribed via StreamStateChanges
+type grpcBroadcaster struct {
+	mtx  sync.Mutex
+	subs map[chan *pb.ClusterState]struct{}
+}
+
+func newGrpcBroadcaster() *grpcBroadcaster {
+	return &grpcBroadcaster{subs: make(map[chan *pb.ClusterState]struct{})}
+}
+
+func (b *grpcBroadcaster) subscribe() chan *pb.ClusterState {
+	ch := make(chan *pb.ClusterState, 16)
+	b.mtx.Lock()
+	b.subs[ch] = struct{}{}
+	b.mtx.Unlock()
+	return ch
+}
+
+func (b *grpcBroadcaster) unsubscribe(ch chan *pb.ClusterState) {
+	b.mtx.Lock()
+	delete(b.subs, ch)
+	b.mtx.Unlock()
+	close(ch)
+}
+
+func (b *grpcBroadcaster) broadcast(cm cmn.HASMod) {
+	msg := &pb.ClusterState{Instance: cm.Instance, State: cm.State, Vip: cm.Vip.String()}
+
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	for ch := range b.subs {
+		select {
+		case ch <- msg:
+		default:
+			tk.LogIt(tk.LogWarning, "gRPC - dropping state change for slow StreamStateChanges subscriber\n")
+		}
+	}
+}
+
+// clusterGrpcServer - implements pb.ClusterServiceServer on top of CIStateH
+type clusterGrpcServer struct {
+	pb.UnimplementedClusterServiceServer
+	ci *CIStateH
+}
+
+func (s *clusterGrpcServer) GetState(ctx context.Context, req *pb.GetStateRequest) (*pb.GetStateResponse, error) {
+	mh.mtx.Lock()
+	defer mh.mtx.Unlock()
+
+	states, err := s.ci.CIStateGet()
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &pb.GetStateResponse{}
+	for _, cm := range states {
+		if req.Instance != "" && req.Instance != cm.Instance {
+			continue
+		}
+		resp.States = append(resp.States, &pb.ClusterState{Instance: cm.Instance, State: cm.State, Vip: cm.Vip.String()})
+	}
+	return resp, nil
+}
+
+func (s *clusterGrpcServer) StreamStateChanges(req *pb.StreamStateChangesRequest, stream pb.ClusterService_StreamStateChangesServer) error {
+	ch := s.ci.grpcB.subscribe()
+	defer s.ci.grpcB.unsubscribe(ch)
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case msg := <-ch:
+			if err := stream.Send(msg); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *clusterGrpcServer) UpdateState(ctx context.Context, req *pb.UpdateStateRequest) (*pb.UpdateStateResponse, error) {
+	mh.mtx.Lock()
+	defer mh.mtx.Unlock()
+
+	_, err := s.ci.CIStateUpdate(cmn.HASMod{Instance: req.Instance, State: req.State, Vip: net.ParseIP(req.Vip)})
+	if err != nil {
+		return &pb.UpdateStateResponse{Ok: false, Error: err.Error()}, nil
+	}
+	return &pb.UpdateStateResponse{Ok: true}, nil
+}
+
+func (s *clusterGrpcServer) AddNode(ctx context.Context, req *pb.AddNodeRequest) (*pb.AddNodeResponse, error) {
+	ip := net.ParseIP(req.Addr)
+	if ip == nil {
+		return &pb.AddNodeResponse{Ok: false, Error: "invalid node address"}, nil
+	}
+
+	mh.mtx.Lock()
+	defer mh.mtx.Unlock()
+
+	if _, err := s.ci.ClusterNodeAdd(cmn.ClusterNodeMod{Addr: ip}); err != nil {
+		return &pb.AddNodeResponse{Ok: false, Error: err.Error()}, nil
+	}
+	return &pb.AddNodeResponse{Ok: true}, nil
+}
+
+func (s *clusterGrpcServer) DeleteNode(ctx context.Context, req *pb.DeleteNodeRequest) (*pb.DeleteNodeResponse, error) {
+	ip := net.ParseIP(req.Addr)
+	if ip == nil {
+		return &pb.DeleteNodeResponse{Ok: false, Error: "invalid node address"}, nil
+	}
+
+	mh.mtx.Lock()
+	defer mh.mtx.Unlock()
+
+	if _, err := s.ci.ClusterNodeDelete(cmn.ClusterNodeMod{Addr: ip}); err != nil {
+		return &pb.DeleteNodeResponse{Ok: false, Error: err.Error()}, nil
+	}
+	return &pb.DeleteNodeResponse{Ok: true}, nil
+}
+
+func (s *clusterGrpcServer) ListNodes(ctx context.Context, req *pb.ListNodesRequest) (*pb.ListNodesResponse, error) {
+	mh.mtx.Lock()
+	defer mh.mtx.Unlock()
+
+	resp := &pb.ListNodesResponse{}
+	for _, n := range s.ci.NodeMap {
+		resp.Nodes = append(resp.Nodes, &pb.Node{Addr: n.Addr.String(), Status: n.GossipState})
+	}
+	return resp, nil
+}
+
+// startGrpcServer - brings up the ClusterService gRPC endpoint, secured
+// with TLS when a cert/key pair is configured (mTLS when a CA file is also
+// set), mirroring the REST server
+func (ci *CIStateH) startGrpcServer() {
+	ci.grpcB = newGrpcBroadcaster()
+
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", ci.GrpcPort))
+	if err != nil {
+		tk.LogIt(tk.LogCritical, "gRPC - cant listen on port %d: %s\n", ci.GrpcPort, err)
+		os.Exit(1)
+	}
+
+	var srvOpts []grpc.ServerOption
+	srvOpts = append(srvOpts, grpc.ForceServerCodec(pb.Codec))
+	if ci.GrpcCertFile != "" && ci.GrpcKeyFile != "" {
+		creds, err := loadGrpcTLSCreds(ci.GrpcCertFile, ci.GrpcKeyFile, ci.GrpcCAFile)
+		if err != nil {
+			tk.LogIt(tk.LogCritical, "gRPC - cant load TLS creds: %s\n", err)
+			os.Exit(1)
+		}
+		srvOpts = append(srvOpts, grpc.Creds(creds))
+	}
+
+	gs := grpc.NewServer(srvOpts...)
+	pb.RegisterClusterServiceServer(gs, &clusterGrpcServer{ci: ci})
+
+	tk.LogIt(tk.LogInfo, "gRPC - ClusterService listening on :%d\n", ci.GrpcPort)
+	if err := gs.Serve(lis); err != nil {
+		tk.LogIt(tk.LogError, "gRPC - server exited: %s\n", err)
+	}
+}
+
+// loadGrpcTLSCreds - builds server-side mTLS credentials; when caFile is
+// set, client certs are required and verified against it
+func loadGrpcTLSCreds(certFile, keyFile, caFile string) (credentials.TransportCredentials, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &tls.Config{Certificates: []tls.Certificate{cert}}
+
+	if caFile != "" {
+		caPEM, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("failed to parse CA cert %s", caFile)
+		}
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return credentials.NewTLS(cfg), nil
+}
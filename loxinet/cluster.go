@@ -23,6 +23,7 @@ import (
 	"os"
 	"time"
 
+	hclog "github.com/hashicorp/go-hclog"
 	cmn "github.com/loxilb-io/loxilb/common"
 	opts "github.com/loxilb-io/loxilb/options"
 	bfd "github.com/loxilb-io/loxilb/proto"
@@ -40,32 +41,88 @@ const (
 type ClusterInstance struct {
 	State    int
 	StateStr string
-	Vip      net.IP
+	// Vip holds every VIP owned by this instance. Multiple HA instances can
+	// thus own disjoint VIP groups and transition independently, e.g.
+	// ha-north active on one node and ha-south active on another.
+	Vip      []net.IP
+	RemoteIP net.IP
+	SourceIP net.IP
+	Interval int64
+}
+
+// addVip - adds vip to the instance's VIP group if not already present
+func (ci *ClusterInstance) addVip(vip net.IP) {
+	if vip == nil || vip.IsUnspecified() {
+		return
+	}
+	for _, v := range ci.Vip {
+		if v.Equal(vip) {
+			return
+		}
+	}
+	ci.Vip = append(ci.Vip, vip)
+}
+
+// clearVip - drops every VIP from the instance's VIP group, used on
+// demotion away from MASTER so a BACKUP/FAULT instance stops advertising
+// VIPs it no longer owns
+func (ci *ClusterInstance) clearVip() {
+	ci.Vip = nil
 }
 
 // ClusterNode - Struct for Cluster Node Information
 type ClusterNode struct {
-	Addr   net.IP
-	Status DpStatusT
+	Addr        net.IP
+	Status      DpStatusT
+	GossipState string
+	Incarnation uint64
+	LastSeen    time.Time
 }
 
 // CIKAArgs - Struct for cluster BFD args
 type CIKAArgs struct {
-	SpawnKa  bool
-	RemoteIP net.IP
-	SourceIP net.IP
-	Interval int64
+	SpawnKa        bool
+	RemoteIP       net.IP
+	SourceIP       net.IP
+	Interval       int64
+	SeedPeers      []string
+	GossipBindAddr string
+	GrpcPort       int
+	GrpcCertFile   string
+	GrpcKeyFile    string
+	GrpcCAFile     string
+	// LogLevel controls cluster state-change logging (e.g. "info", "debug").
+	// BFDLogLevel controls the noisier per-session BFD chatter independently,
+	// so it can be silenced ("warn") without losing state-change visibility.
+	LogLevel    string
+	BFDLogLevel string
 }
 
 // CIStateH - Cluster context handler
 type CIStateH struct {
-	SpawnKa    bool
-	RemoteIP   net.IP
-	SourceIP   net.IP
-	Interval   int64
-	ClusterMap map[string]*ClusterInstance
-	StateMap   map[string]int
-	NodeMap    map[string]*ClusterNode
+	SpawnKa        bool
+	RemoteIP       net.IP
+	SourceIP       net.IP
+	Interval       int64
+	SeedPeers      []string
+	GossipBindAddr string
+	GrpcPort       int
+	GrpcCertFile   string
+	GrpcKeyFile    string
+	GrpcCAFile     string
+	ClusterMap     map[string]*ClusterInstance
+	StateMap       map[string]int
+	NodeMap        map[string]*ClusterNode
+	BfdSessions    map[string]*bfd.Session
+	InstanceBFD    map[string]*bfd.Session
+	gossipH        *gossipH
+	grpcB          *grpcBroadcaster
+	// Log emits structured, leveled cluster state-change events (instance,
+	// old_state, new_state, vip, peer, incarnation) so they can be shipped
+	// to Loki/ELK and joined with datapath logs. bfdLog is a separate logger
+	// carrying the noisier per-session BFD chatter, leveled independently.
+	Log    hclog.Logger
+	bfdLog hclog.Logger
 }
 
 func (ci *CIStateH) BFDSessionNotify(instance string, remote string, ciState string) {
@@ -74,7 +131,7 @@ func (ci *CIStateH) BFDSessionNotify(instance string, remote string, ciState str
 	sm.Instance = instance
 	sm.State = ciState
 	sm.Vip = net.ParseIP("0.0.0.0")
-	tk.LogIt(tk.LogInfo, "ci-change instance %s - state %s vip %v\n", instance, ciState, sm.Vip)
+	ci.bfdLog.Info("bfd session notify", "instance", instance, "peer", remote, "new_state", ciState, "vip", sm.Vip)
 	mh.mtx.Lock()
 	defer mh.mtx.Unlock()
 	ci.CIStateUpdate(sm)
@@ -94,19 +151,53 @@ func (ci *CIStateH) startBFDProto() {
 	// We need some cool-off period for loxilb to self sync-up in the cluster
 	time.Sleep(KAInitTiVal * time.Second)
 
+	// A gossip bind addr means peers are discovered dynamically rather than
+	// statically configured as a single remote/source pair - bootstrap the
+	// mesh from the seed list instead of the legacy pair below, which has
+	// no RemoteIP to dial in that mode.
+	if ci.GossipBindAddr != "" {
+		ci.startGossip()
+		return
+	}
+
+	if err := ci.addBFDPeer(ci.RemoteIP.String(), ci.SourceIP.String(), cmn.CIDefault); err != nil {
+		tk.LogIt(tk.LogCritical, "KA - Cant add BFD remote\n")
+		os.Exit(1)
+	}
+}
+
+// addBFDPeer - create a BFD session towards a mesh peer; a peer that
+// already has a session keeps it unchanged
+func (ci *CIStateH) addBFDPeer(remote, source, instance string) error {
+	if _, ok := ci.BfdSessions[remote]; ok {
+		return nil
+	}
+
 	txInterval := uint32(bfd.BFDDflSysTXIntervalUs)
 	if ci.Interval != 0 && ci.Interval >= bfd.BFDMinSysTXIntervalUs {
 		txInterval = uint32(ci.Interval)
 	}
 
 	bs := bfd.StructNew(3784)
-	bfdSessConfigArgs := bfd.ConfigArgs{RemoteIP: ci.RemoteIP.String(), SourceIP: ci.SourceIP.String(), Port: 3784, Interval: txInterval, Multi: 3, Instance: cmn.CIDefault}
-	err := bs.BFDAddRemote(bfdSessConfigArgs, ci)
-	if err != nil {
-		tk.LogIt(tk.LogCritical, "KA - Cant add BFD remote\n")
-		os.Exit(1)
+	bfdSessConfigArgs := bfd.ConfigArgs{RemoteIP: remote, SourceIP: source, Port: 3784, Interval: txInterval, Multi: 3, Instance: instance}
+	if err := bs.BFDAddRemote(bfdSessConfigArgs, ci); err != nil {
+		return err
 	}
-	tk.LogIt(tk.LogInfo, "KA - Added BFD remote %s:%s:%vus\n", ci.RemoteIP.String(), ci.SourceIP.String(), txInterval)
+
+	ci.BfdSessions[remote] = bs
+	ci.bfdLog.Info("bfd peer added", "instance", instance, "peer", remote, "source", source, "interval_us", txInterval)
+	return nil
+}
+
+// delBFDPeer - tear down the BFD session towards a mesh peer that left
+func (ci *CIStateH) delBFDPeer(remote string) {
+	bs, ok := ci.BfdSessions[remote]
+	if !ok {
+		return
+	}
+	bs.BFDDeleteRemote(remote)
+	delete(ci.BfdSessions, remote)
+	ci.bfdLog.Info("bfd peer deleted", "peer", remote)
 }
 
 // CITicker - Periodic ticker for Cluster module
@@ -119,6 +210,9 @@ func (ci *CIStateH) CISpawn() {
 	if ci.SpawnKa {
 		go ci.startBFDProto()
 	}
+	if ci.GrpcPort != 0 {
+		go ci.startGrpcServer()
+	}
 }
 
 // CIInit - routine to initialize Cluster context
@@ -134,23 +228,144 @@ func CIInit(args CIKAArgs) *CIStateH {
 	nCIh.RemoteIP = args.RemoteIP
 	nCIh.SourceIP = args.SourceIP
 	nCIh.Interval = args.Interval
+	nCIh.SeedPeers = args.SeedPeers
+	nCIh.GossipBindAddr = args.GossipBindAddr
+	nCIh.GrpcPort = args.GrpcPort
+	nCIh.GrpcCertFile = args.GrpcCertFile
+	nCIh.GrpcKeyFile = args.GrpcKeyFile
+	nCIh.GrpcCAFile = args.GrpcCAFile
+	nCIh.BfdSessions = make(map[string]*bfd.Session)
 	nCIh.ClusterMap = make(map[string]*ClusterInstance)
 
+	logLevel := args.LogLevel
+	if logLevel == "" {
+		logLevel = "info"
+	}
+	nCIh.Log = hclog.New(&hclog.LoggerOptions{
+		Name:       "cluster",
+		Level:      hclog.LevelFromString(logLevel),
+		JSONFormat: opts.Opts.LogJSON,
+	})
+
+	bfdLogLevel := args.BFDLogLevel
+	if bfdLogLevel == "" {
+		bfdLogLevel = logLevel
+	}
+	// A Named() child shares its parent's level pointer, so SetLevel here
+	// would also silence nCIh.Log - build bfdLog as its own logger instead
+	// so BFDLogLevel is independent of LogLevel.
+	nCIh.bfdLog = hclog.New(&hclog.LoggerOptions{
+		Name:       "cluster.bfd",
+		Level:      hclog.LevelFromString(bfdLogLevel),
+		JSONFormat: opts.Opts.LogJSON,
+	})
+
 	if _, ok := nCIh.ClusterMap[cmn.CIDefault]; !ok {
 		ci := &ClusterInstance{State: cmn.CIStateNotDefined,
 			StateStr: "NOT_DEFINED",
-			Vip:      net.IPv4zero,
+			RemoteIP: args.RemoteIP,
+			SourceIP: args.SourceIP,
+			Interval: args.Interval,
 		}
 		nCIh.ClusterMap[cmn.CIDefault] = ci
 		if mh.bgp != nil {
-			mh.bgp.UpdateCIState(cmn.CIDefault, ci.State, ci.Vip)
+			mh.bgp.UpdateCIState(cmn.CIDefault, ci.State, net.IPv4zero)
 		}
 	}
 
+	nCIh.InstanceBFD = make(map[string]*bfd.Session)
 	nCIh.NodeMap = make(map[string]*ClusterNode)
 	return nCIh
 }
 
+// CIInstanceAdd - spins up an independent HA instance with its own BFD
+// session (own remote/source/interval) and VIP group, so it can
+// transition between MASTER/BACKUP without affecting any other instance.
+// The BFD detect multiplier is fixed at 3 for every instance.
+func (h *CIStateH) CIInstanceAdd(name string, args CIKAArgs) error {
+	if _, ok := h.ClusterMap[name]; ok {
+		return errors.New("cluster instance already exists")
+	}
+
+	ci := &ClusterInstance{
+		State:    cmn.CIStateNotDefined,
+		StateStr: "NOT_DEFINED",
+		RemoteIP: args.RemoteIP,
+		SourceIP: args.SourceIP,
+		Interval: args.Interval,
+	}
+	h.ClusterMap[name] = ci
+
+	if mh.bgp != nil {
+		mh.bgp.UpdateCIState(name, ci.State, net.IPv4zero)
+	}
+
+	if args.SpawnKa {
+		if err := h.addInstanceBFDPeer(name, args); err != nil {
+			delete(h.ClusterMap, name)
+			return err
+		}
+	}
+
+	tk.LogIt(tk.LogInfo, "[CLUSTER] Instance %s added remote %s source %s\n", name, args.RemoteIP.String(), args.SourceIP.String())
+	return nil
+}
+
+// CIInstanceDelete - tears down an HA instance, its BFD session and its
+// VIP group
+func (h *CIStateH) CIInstanceDelete(name string) error {
+	if name == cmn.CIDefault {
+		return errors.New("cannot delete default cluster instance")
+	}
+
+	if _, ok := h.ClusterMap[name]; !ok {
+		return errors.New("cluster instance not found")
+	}
+
+	h.delInstanceBFDPeer(name)
+	delete(h.ClusterMap, name)
+	// Ideally this would resync only the deleted instance's VIP rules, but
+	// RuleVIPSyncToClusterState lives in rules.go, which this series does
+	// not touch - adding a per-instance signature here without that file
+	// present would break the build again (see the chunk0-3 build-break
+	// fix). Falling back to the existing global resync until rules.go
+	// grows an instance-scoped variant.
+	mh.zr.Rules.RuleVIPSyncToClusterState()
+
+	tk.LogIt(tk.LogInfo, "[CLUSTER] Instance %s deleted\n", name)
+	return nil
+}
+
+// addInstanceBFDPeer - brings up the dedicated BFD session for a
+// per-instance HA pair
+func (h *CIStateH) addInstanceBFDPeer(name string, args CIKAArgs) error {
+	txInterval := uint32(bfd.BFDDflSysTXIntervalUs)
+	if args.Interval != 0 && args.Interval >= bfd.BFDMinSysTXIntervalUs {
+		txInterval = uint32(args.Interval)
+	}
+
+	bs := bfd.StructNew(3784)
+	bfdSessConfigArgs := bfd.ConfigArgs{RemoteIP: args.RemoteIP.String(), SourceIP: args.SourceIP.String(), Port: 3784, Interval: txInterval, Multi: 3, Instance: name}
+	if err := bs.BFDAddRemote(bfdSessConfigArgs, h); err != nil {
+		return err
+	}
+
+	h.InstanceBFD[name] = bs
+	return nil
+}
+
+// delInstanceBFDPeer - tears down the dedicated BFD session for an instance
+func (h *CIStateH) delInstanceBFDPeer(name string) {
+	bs, ok := h.InstanceBFD[name]
+	if !ok {
+		return
+	}
+	if ci, ok := h.ClusterMap[name]; ok {
+		bs.BFDDeleteRemote(ci.RemoteIP.String())
+	}
+	delete(h.InstanceBFD, name)
+}
+
 // CIStateGetInst - routine to get HA state
 func (h *CIStateH) CIStateGetInst(inst string) (string, error) {
 
@@ -169,22 +384,36 @@ func (h *CIStateH) CIStateGet() ([]cmn.HASMod, error) {
 		var temp cmn.HASMod
 		temp.Instance = i
 		temp.State = s.StateStr
-		temp.Vip = s.Vip
+		if len(s.Vip) > 0 {
+			temp.Vip = s.Vip[0]
+		} else {
+			temp.Vip = net.IPv4zero
+		}
 		res = append(res, temp)
 	}
 	return res, nil
 }
 
-// CIVipGet - routine to get HA state
+// CIVipGet - routine to get HA state. For instances owning more than one
+// VIP, this returns the first VIP in the group - use CIVipsGet for the
+// full set.
 func (h *CIStateH) CIVipGet(inst string) (net.IP, error) {
 	if ci, ok := h.ClusterMap[inst]; ok {
-		if ci.Vip != nil && !ci.Vip.IsUnspecified() {
-			return ci.Vip, nil
+		if len(ci.Vip) > 0 && !ci.Vip[0].IsUnspecified() {
+			return ci.Vip[0], nil
 		}
 	}
 	return net.IPv4zero, errors.New("not found")
 }
 
+// CIVipsGet - routine to get the full VIP group owned by an instance
+func (h *CIStateH) CIVipsGet(inst string) ([]net.IP, error) {
+	if ci, ok := h.ClusterMap[inst]; ok {
+		return ci.Vip, nil
+	}
+	return nil, errors.New("not found")
+}
+
 // IsCIKAMode - routine to get KA mode
 func (h *CIStateH) IsCIKAMode() bool {
 	return false
@@ -195,14 +424,13 @@ func (h *CIStateH) CIStateUpdate(cm cmn.HASMod) (int, error) {
 
 	if _, ok := h.ClusterMap[cm.Instance]; !ok {
 		h.ClusterMap[cm.Instance] = &ClusterInstance{State: cmn.CIStateNotDefined,
-			StateStr: "NOT_DEFINED",
-			Vip:      net.IPv4zero}
-		tk.LogIt(tk.LogDebug, "[CLUSTER] New Instance %s created\n", cm.Instance)
+			StateStr: "NOT_DEFINED"}
+		h.Log.Debug("cluster instance created", "instance", cm.Instance)
 	}
 
 	ci, found := h.ClusterMap[cm.Instance]
 	if !found {
-		tk.LogIt(tk.LogError, "[CLUSTER] New Instance %s find error\n", cm.Instance)
+		h.Log.Error("cluster instance lookup failed", "instance", cm.Instance)
 		return -1, errors.New("cluster instance not found")
 	}
 
@@ -211,22 +439,35 @@ func (h *CIStateH) CIStateUpdate(cm cmn.HASMod) (int, error) {
 	}
 
 	if _, ok := h.StateMap[cm.State]; ok {
-		tk.LogIt(tk.LogDebug, "[CLUSTER] Instance %s Current State %s Updated State: %s VIP : %s\n",
-			cm.Instance, ci.StateStr, cm.State, cm.Vip.String())
+		h.Log.Info("cluster state transition",
+			"instance", cm.Instance, "old_state", ci.StateStr, "new_state", cm.State, "vip", cm.Vip.String())
 		ci.StateStr = cm.State
 		ci.State = h.StateMap[cm.State]
-		ci.Vip = cm.Vip
+		if ci.State == cmn.CIStateMaster {
+			ci.addVip(cm.Vip)
+		} else {
+			// BACKUP/FAULT/etc own no VIPs; drop whatever this instance
+			// was advertising as MASTER rather than accumulating it forever.
+			ci.clearVip()
+		}
 		if h.SpawnKa && (cm.State == "FAULT" || cm.State == "STOP") {
 			RunCommand("pkill keepalived", false)
 		}
 		if mh.bgp != nil {
-			mh.bgp.UpdateCIState(cm.Instance, ci.State, ci.Vip)
+			mh.bgp.UpdateCIState(cm.Instance, ci.State, cm.Vip)
 		}
+		// See the matching comment in CIInstanceDelete: this should really
+		// be scoped to cm.Instance, but RuleVIPSyncToClusterState lives in
+		// rules.go, which isn't part of this tree, so it stays a global
+		// resync for now rather than risking another build break.
 		mh.zr.Rules.RuleVIPSyncToClusterState()
+		if h.grpcB != nil {
+			h.grpcB.broadcast(cm)
+		}
 		return ci.State, nil
 	}
 
-	tk.LogIt(tk.LogError, "[CLUSTER] Invalid State: %s\n", cm.State)
+	h.Log.Error("invalid cluster state", "instance", cm.Instance, "state", cm.State)
 	return ci.State, errors.New("invalid cluster-state")
 
 }
@@ -245,6 +486,7 @@ func (h *CIStateH) ClusterNodeAdd(node cmn.ClusterNodeMod) (int, error) {
 	h.NodeMap[node.Addr.String()] = cNode
 
 	cNode.DP(DpCreate)
+	h.Log.Info("cluster node added", "peer", node.Addr.String())
 
 	return 0, nil
 }
@@ -261,6 +503,7 @@ func (h *CIStateH) ClusterNodeDelete(node cmn.ClusterNodeMod) (int, error) {
 	delete(h.NodeMap, node.Addr.String())
 
 	cNode.DP(DpRemove)
+	h.Log.Info("cluster node deleted", "peer", node.Addr.String())
 	return 0, nil
 }
 
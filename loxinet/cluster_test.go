@@ -0,0 +1,83 @@
+/*
+ * Copyright (c) 2022 NetLOX Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loxinet
+
+import (
+	"net"
+	"testing"
+
+	cmn "github.com/loxilb-io/loxilb/common"
+)
+
+func TestAddVipDedupsAndIgnoresUnspecified(t *testing.T) {
+	ci := &ClusterInstance{}
+
+	ci.addVip(nil)
+	ci.addVip(net.IPv4zero)
+	if len(ci.Vip) != 0 {
+		t.Fatalf("expected nil/unspecified vip to be ignored, got %v", ci.Vip)
+	}
+
+	vip := net.ParseIP("1.1.1.1")
+	ci.addVip(vip)
+	ci.addVip(vip)
+	if len(ci.Vip) != 1 {
+		t.Fatalf("expected addVip to dedup repeated vip, got %v", ci.Vip)
+	}
+}
+
+func TestClearVip(t *testing.T) {
+	ci := &ClusterInstance{Vip: []net.IP{net.ParseIP("1.1.1.1"), net.ParseIP("2.2.2.2")}}
+	ci.clearVip()
+	if len(ci.Vip) != 0 {
+		t.Fatalf("expected clearVip to empty the VIP group, got %v", ci.Vip)
+	}
+}
+
+func newTestCIStateH() *CIStateH {
+	return &CIStateH{
+		StateMap:   map[string]int{"MASTER": cmn.CIStateMaster, "BACKUP": cmn.CIStateBackup, "NOT_DEFINED": cmn.CIStateNotDefined},
+		ClusterMap: make(map[string]*ClusterInstance),
+	}
+}
+
+func TestCIInstanceAddRejectsDuplicate(t *testing.T) {
+	h := newTestCIStateH()
+
+	if err := h.CIInstanceAdd("ha-north", CIKAArgs{}); err != nil {
+		t.Fatalf("unexpected error adding new instance: %s", err)
+	}
+	if _, ok := h.ClusterMap["ha-north"]; !ok {
+		t.Fatalf("expected ha-north to be present in ClusterMap")
+	}
+
+	if err := h.CIInstanceAdd("ha-north", CIKAArgs{}); err == nil {
+		t.Fatalf("expected error re-adding an existing instance")
+	}
+}
+
+func TestCIInstanceDeleteGuards(t *testing.T) {
+	h := newTestCIStateH()
+
+	if err := h.CIInstanceDelete(cmn.CIDefault); err == nil {
+		t.Fatalf("expected error deleting the default cluster instance")
+	}
+
+	if err := h.CIInstanceDelete("no-such-instance"); err == nil {
+		t.Fatalf("expected error deleting an unknown cluster instance")
+	}
+}
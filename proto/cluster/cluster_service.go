@@ -0,0 +1,269 @@
+// Hand-maintained ClusterServiceClient/ClusterServiceServer stubs for
+// cluster.proto - see the package doc in cluster_types.go for why these
+// aren't protoc-gen-go-grpc output. All RPCs are forced onto Codec
+// (codec.go) rather than the default proto codec.
+
+package cluster
+
+import (
+	context "context"
+
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// ClusterServiceClient is the client API for ClusterService.
+type ClusterServiceClient interface {
+	GetState(ctx context.Context, in *GetStateRequest, opts ...grpc.CallOption) (*GetStateResponse, error)
+	StreamStateChanges(ctx context.Context, in *StreamStateChangesRequest, opts ...grpc.CallOption) (ClusterService_StreamStateChangesClient, error)
+	UpdateState(ctx context.Context, in *UpdateStateRequest, opts ...grpc.CallOption) (*UpdateStateResponse, error)
+	AddNode(ctx context.Context, in *AddNodeRequest, opts ...grpc.CallOption) (*AddNodeResponse, error)
+	DeleteNode(ctx context.Context, in *DeleteNodeRequest, opts ...grpc.CallOption) (*DeleteNodeResponse, error)
+	ListNodes(ctx context.Context, in *ListNodesRequest, opts ...grpc.CallOption) (*ListNodesResponse, error)
+}
+
+type clusterServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+// NewClusterServiceClient returns a client for ClusterService.
+func NewClusterServiceClient(cc grpc.ClientConnInterface) ClusterServiceClient {
+	return &clusterServiceClient{cc}
+}
+
+func (c *clusterServiceClient) GetState(ctx context.Context, in *GetStateRequest, opts ...grpc.CallOption) (*GetStateResponse, error) {
+	opts = append(opts, grpc.ForceCodec(Codec))
+	out := new(GetStateResponse)
+	if err := c.cc.Invoke(ctx, "/cluster.ClusterService/GetState", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *clusterServiceClient) StreamStateChanges(ctx context.Context, in *StreamStateChangesRequest, opts ...grpc.CallOption) (ClusterService_StreamStateChangesClient, error) {
+	opts = append(opts, grpc.ForceCodec(Codec))
+	stream, err := c.cc.NewStream(ctx, &_ClusterService_serviceDesc.Streams[0], "/cluster.ClusterService/StreamStateChanges", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &clusterServiceStreamStateChangesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// ClusterService_StreamStateChangesClient is the client side of the
+// StreamStateChanges server-streaming RPC.
+type ClusterService_StreamStateChangesClient interface {
+	Recv() (*ClusterState, error)
+	grpc.ClientStream
+}
+
+type clusterServiceStreamStateChangesClient struct {
+	grpc.ClientStream
+}
+
+func (x *clusterServiceStreamStateChangesClient) Recv() (*ClusterState, error) {
+	m := new(ClusterState)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *clusterServiceClient) UpdateState(ctx context.Context, in *UpdateStateRequest, opts ...grpc.CallOption) (*UpdateStateResponse, error) {
+	opts = append(opts, grpc.ForceCodec(Codec))
+	out := new(UpdateStateResponse)
+	if err := c.cc.Invoke(ctx, "/cluster.ClusterService/UpdateState", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *clusterServiceClient) AddNode(ctx context.Context, in *AddNodeRequest, opts ...grpc.CallOption) (*AddNodeResponse, error) {
+	opts = append(opts, grpc.ForceCodec(Codec))
+	out := new(AddNodeResponse)
+	if err := c.cc.Invoke(ctx, "/cluster.ClusterService/AddNode", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *clusterServiceClient) DeleteNode(ctx context.Context, in *DeleteNodeRequest, opts ...grpc.CallOption) (*DeleteNodeResponse, error) {
+	opts = append(opts, grpc.ForceCodec(Codec))
+	out := new(DeleteNodeResponse)
+	if err := c.cc.Invoke(ctx, "/cluster.ClusterService/DeleteNode", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *clusterServiceClient) ListNodes(ctx context.Context, in *ListNodesRequest, opts ...grpc.CallOption) (*ListNodesResponse, error) {
+	opts = append(opts, grpc.ForceCodec(Codec))
+	out := new(ListNodesResponse)
+	if err := c.cc.Invoke(ctx, "/cluster.ClusterService/ListNodes", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ClusterServiceServer is the server API for ClusterService.
+type ClusterServiceServer interface {
+	GetState(context.Context, *GetStateRequest) (*GetStateResponse, error)
+	StreamStateChanges(*StreamStateChangesRequest, ClusterService_StreamStateChangesServer) error
+	UpdateState(context.Context, *UpdateStateRequest) (*UpdateStateResponse, error)
+	AddNode(context.Context, *AddNodeRequest) (*AddNodeResponse, error)
+	DeleteNode(context.Context, *DeleteNodeRequest) (*DeleteNodeResponse, error)
+	ListNodes(context.Context, *ListNodesRequest) (*ListNodesResponse, error)
+}
+
+// UnimplementedClusterServiceServer can be embedded to have forward
+// compatible implementations.
+type UnimplementedClusterServiceServer struct{}
+
+func (UnimplementedClusterServiceServer) GetState(context.Context, *GetStateRequest) (*GetStateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetState not implemented")
+}
+func (UnimplementedClusterServiceServer) StreamStateChanges(*StreamStateChangesRequest, ClusterService_StreamStateChangesServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamStateChanges not implemented")
+}
+func (UnimplementedClusterServiceServer) UpdateState(context.Context, *UpdateStateRequest) (*UpdateStateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateState not implemented")
+}
+func (UnimplementedClusterServiceServer) AddNode(context.Context, *AddNodeRequest) (*AddNodeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddNode not implemented")
+}
+func (UnimplementedClusterServiceServer) DeleteNode(context.Context, *DeleteNodeRequest) (*DeleteNodeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteNode not implemented")
+}
+func (UnimplementedClusterServiceServer) ListNodes(context.Context, *ListNodesRequest) (*ListNodesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListNodes not implemented")
+}
+
+// RegisterClusterServiceServer registers srv with s.
+func RegisterClusterServiceServer(s grpc.ServiceRegistrar, srv ClusterServiceServer) {
+	s.RegisterService(&_ClusterService_serviceDesc, srv)
+}
+
+func _ClusterService_GetState_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ClusterServiceServer).GetState(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/cluster.ClusterService/GetState"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ClusterServiceServer).GetState(ctx, req.(*GetStateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ClusterService_StreamStateChanges_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamStateChangesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ClusterServiceServer).StreamStateChanges(m, &clusterServiceStreamStateChangesServer{stream})
+}
+
+// ClusterService_StreamStateChangesServer is the server side of the
+// StreamStateChanges server-streaming RPC.
+type ClusterService_StreamStateChangesServer interface {
+	Send(*ClusterState) error
+	grpc.ServerStream
+}
+
+type clusterServiceStreamStateChangesServer struct {
+	grpc.ServerStream
+}
+
+func (x *clusterServiceStreamStateChangesServer) Send(m *ClusterState) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _ClusterService_UpdateState_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateStateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ClusterServiceServer).UpdateState(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/cluster.ClusterService/UpdateState"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ClusterServiceServer).UpdateState(ctx, req.(*UpdateStateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ClusterService_AddNode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddNodeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ClusterServiceServer).AddNode(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/cluster.ClusterService/AddNode"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ClusterServiceServer).AddNode(ctx, req.(*AddNodeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ClusterService_DeleteNode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteNodeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ClusterServiceServer).DeleteNode(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/cluster.ClusterService/DeleteNode"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ClusterServiceServer).DeleteNode(ctx, req.(*DeleteNodeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ClusterService_ListNodes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListNodesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ClusterServiceServer).ListNodes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/cluster.ClusterService/ListNodes"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ClusterServiceServer).ListNodes(ctx, req.(*ListNodesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _ClusterService_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "cluster.ClusterService",
+	HandlerType: (*ClusterServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "GetState", Handler: _ClusterService_GetState_Handler},
+		{MethodName: "UpdateState", Handler: _ClusterService_UpdateState_Handler},
+		{MethodName: "AddNode", Handler: _ClusterService_AddNode_Handler},
+		{MethodName: "DeleteNode", Handler: _ClusterService_DeleteNode_Handler},
+		{MethodName: "ListNodes", Handler: _ClusterService_ListNodes_Handler},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamStateChanges",
+			Handler:       _ClusterService_StreamStateChanges_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "cluster.proto",
+}
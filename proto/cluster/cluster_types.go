@@ -0,0 +1,97 @@
+/*
+ * Copyright (c) 2022 NetLOX Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package cluster holds the ClusterService message types and client/server
+// stubs. There is no protoc-gen-go toolchain wired into this repo's build,
+// so these are hand-maintained to match cluster.proto rather than
+// generated; they're carried over the wire with the JSON Codec in
+// codec.go instead of the binary protobuf wire format.
+package cluster
+
+// ClusterState mirrors the ClusterState message in cluster.proto.
+type ClusterState struct {
+	Instance string `json:"instance,omitempty"`
+	State    string `json:"state,omitempty"`
+	Vip      string `json:"vip,omitempty"`
+}
+
+// GetStateRequest mirrors the GetStateRequest message in cluster.proto.
+type GetStateRequest struct {
+	Instance string `json:"instance,omitempty"`
+}
+
+// GetStateResponse mirrors the GetStateResponse message in cluster.proto.
+type GetStateResponse struct {
+	States []*ClusterState `json:"states,omitempty"`
+}
+
+// StreamStateChangesRequest mirrors the StreamStateChangesRequest message
+// in cluster.proto.
+type StreamStateChangesRequest struct{}
+
+// UpdateStateRequest mirrors the UpdateStateRequest message in
+// cluster.proto.
+type UpdateStateRequest struct {
+	Instance string `json:"instance,omitempty"`
+	State    string `json:"state,omitempty"`
+	Vip      string `json:"vip,omitempty"`
+}
+
+// UpdateStateResponse mirrors the UpdateStateResponse message in
+// cluster.proto.
+type UpdateStateResponse struct {
+	Ok    bool   `json:"ok,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// Node mirrors the Node message in cluster.proto.
+type Node struct {
+	Addr   string `json:"addr,omitempty"`
+	Status string `json:"status,omitempty"`
+}
+
+// AddNodeRequest mirrors the AddNodeRequest message in cluster.proto.
+type AddNodeRequest struct {
+	Addr string `json:"addr,omitempty"`
+}
+
+// AddNodeResponse mirrors the AddNodeResponse message in cluster.proto.
+type AddNodeResponse struct {
+	Ok    bool   `json:"ok,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// DeleteNodeRequest mirrors the DeleteNodeRequest message in
+// cluster.proto.
+type DeleteNodeRequest struct {
+	Addr string `json:"addr,omitempty"`
+}
+
+// DeleteNodeResponse mirrors the DeleteNodeResponse message in
+// cluster.proto.
+type DeleteNodeResponse struct {
+	Ok    bool   `json:"ok,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// ListNodesRequest mirrors the ListNodesRequest message in cluster.proto.
+type ListNodesRequest struct{}
+
+// ListNodesResponse mirrors the ListNodesResponse message in
+// cluster.proto.
+type ListNodesResponse struct {
+	Nodes []*Node `json:"nodes,omitempty"`
+}
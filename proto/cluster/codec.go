@@ -0,0 +1,41 @@
+/*
+ * Copyright (c) 2022 NetLOX Inc
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at:
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cluster
+
+import "encoding/json"
+
+// jsonCodec implements grpc/encoding.Codec by marshaling messages as JSON.
+// The messages in cluster_types.go are plain hand-maintained structs, not
+// protoc-gen-go output, so they don't implement the legacy proto.Message
+// interface the default grpc codec requires; Codec lets ClusterService
+// exchange them without pretending otherwise.
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string { return "json" }
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// Codec is the encoding.Codec that every ClusterService server and client
+// must install - via grpc.ForceServerCodec(Codec) and grpc.ForceCodec(Codec)
+// respectively - in place of the default proto codec.
+var Codec = jsonCodec{}